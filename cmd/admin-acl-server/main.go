@@ -0,0 +1,110 @@
+// Command admin-acl-server runs the HTTP and (optionally) gRPC APIs from the
+// server package as a long-lived daemon, so profiles can be managed from a
+// web UI or automation instead of only from a Go program embedding the index
+// package directly.
+package main
+
+import (
+	"flag"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/goodieshq/headscale-admin-acl/index"
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+	"github.com/goodieshq/headscale-admin-acl/index/history"
+	"github.com/goodieshq/headscale-admin-acl/server"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func main() {
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	var (
+		dataDir     = flag.String("data-dir", ".", "directory holding profiles/ and the index")
+		aclFile     = flag.String("acl-file", "acl.hujson", "path of the headscale ACL file to update on Apply")
+		listenAddr  = flag.String("listen", "", "TCP address to serve on, e.g. :8080 (mutually exclusive with -socket)")
+		socketPath  = flag.String("socket", "", "Unix socket path to serve on (mutually exclusive with -listen)")
+		socketGID   = flag.Int("socket-gid", -1, "group to own -socket; -1 leaves it unchanged")
+		grpcAddr    = flag.String("grpc-listen", "", "TCP address to serve the gRPC API on, e.g. :8081; empty disables it")
+		token       = flag.String("token", "", "bearer token required on every request; empty disables auth (not recommended)")
+		reloadPid   = flag.Int("reload-pid", 0, "headscale process id to SIGHUP after Apply; 0 disables")
+		reloadURL   = flag.String("reload-url", "", "headscale API base URL to PUT the applied policy to (/api/v1/policy) after Apply, e.g. https://headscale.internal")
+		reloadToken = flag.String("reload-api-key", "", "API key for -reload-url")
+		signingKey  = flag.String("history-signing-key", "", "path to an ASCII-armored OpenPGP private key to GPG-sign history commits with; empty leaves commits unsigned")
+		signingPass = flag.String("history-signing-key-passphrase", "", "passphrase for -history-signing-key, if it is encrypted")
+	)
+	flag.Parse()
+
+	if (*listenAddr == "") == (*socketPath == "") {
+		log.Fatal().Msg("exactly one of -listen or -socket must be set")
+	}
+
+	// the index builds its keys under the data dir and FSBackend joins
+	// relative keys onto its root, so a relative dir would be applied twice
+	dir, err := filepath.Abs(*dataDir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("resolving -data-dir")
+	}
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		log.Fatal().Err(err).Msg("opening backend")
+	}
+
+	var historyOpts []history.Option
+	if *signingKey != "" {
+		var passphrase []byte
+		if *signingPass != "" {
+			passphrase = []byte(*signingPass)
+		}
+		key, err := history.LoadSigningKey(*signingKey, passphrase)
+		if err != nil {
+			log.Fatal().Err(err).Msg("loading history signing key")
+		}
+		historyOpts = append(historyOpts, history.WithSigningKey(key))
+	}
+
+	idx, err := index.CreateNewIndex(dir, *aclFile, be, nil, historyOpts...)
+	if err != nil {
+		log.Fatal().Err(err).Msg("opening index")
+	}
+
+	if *token == "" {
+		log.Warn().Msg("running without -token: every request is unauthenticated")
+	}
+
+	opts := []server.Option{server.WithToken(*token)}
+	if *reloadPid != 0 {
+		opts = append(opts, server.WithReloader(server.SignalReloader{Pid: *reloadPid}))
+	} else if *reloadURL != "" {
+		opts = append(opts, server.WithReloader(server.HeadscaleAPIReloader{BaseURL: *reloadURL, APIKey: *reloadToken}))
+	}
+
+	svc := server.New(idx, opts...)
+
+	if *grpcAddr != "" {
+		l, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatal().Err(err).Msg("opening grpc listener")
+		}
+		go func() {
+			log.Info().Str("addr", *grpcAddr).Msg("serving grpc")
+			log.Fatal().Err(svc.GRPCServer().Serve(l)).Send()
+		}()
+	}
+
+	if *socketPath != "" {
+		l, err := server.ListenUnix(*socketPath, *socketGID)
+		if err != nil {
+			log.Fatal().Err(err).Msg("opening unix socket")
+		}
+		log.Info().Str("socket", *socketPath).Msg("serving")
+		log.Fatal().Err(http.Serve(l, svc.Handler())).Send()
+	}
+
+	log.Info().Str("addr", *listenAddr).Msg("serving")
+	log.Fatal().Err(http.ListenAndServe(*listenAddr, svc.Handler())).Send()
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 
 	"github.com/goodieshq/headscale-admin-acl/index"
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -13,20 +15,27 @@ import (
 func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
 
-	idx, err := index.CreateNewIndex("./", "./test.json")
+	ctx := context.Background()
+
+	fsBackend, err := backend.NewFSBackend("./")
+	if err != nil {
+		log.Fatal().Err(err).Send()
+	}
+
+	idx, err := index.CreateNewIndex("./", "./test.json", fsBackend, nil)
 	if err != nil {
 		log.Fatal().Err(err).Send()
 	}
 
-	if err := idx.Set("test1", []byte("testing 123")); err != nil {
+	if err := idx.Set(ctx, "test1", []byte("testing 123")); err != nil {
 		log.Fatal().Err(err).Send()
 	}
 
-	if err := idx.Set("test2", []byte("123 testing")); err != nil {
+	if err := idx.Set(ctx, "test2", []byte("123 testing")); err != nil {
 		log.Fatal().Err(err).Send()
 	}
 
-	if err := idx.RenameProfile("test2", "test3"); err != nil {
+	if err := idx.RenameProfile(ctx, "test2", "test3"); err != nil {
 		if errors.Is(err, index.ErrProfileExists) {
 			log.Fatal().Msg("cannot overwrite existing profiles")
 		}
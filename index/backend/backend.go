@@ -0,0 +1,29 @@
+// Package backend abstracts where profiles and the applied ACL file live,
+// so index.Index can manage them the same way whether they're on local
+// disk, in object storage, in a SQL database, or in a remote git repo.
+package backend
+
+import "errors"
+
+// ErrNotFound is returned by Get (and wrapped errors from it) when key does
+// not exist, regardless of which Backend implementation is in use.
+var ErrNotFound = errors.New("backend: key not found")
+
+// Backend stores opaque blobs by key. Keys are the same path-like strings
+// index.Index already used when it talked to the filesystem directly (e.g.
+// "profiles/index.json", "profiles/<name>-<hash>.hujson"), so existing
+// callers don't need to change shape, just how they're plumbed in.
+type Backend interface {
+	Get(key string) ([]byte, error)
+	Put(key string, data []byte) error
+	Delete(key string) error
+	List() ([]string, error)
+
+	// Lock/Unlock guard a critical section across concurrent writers that
+	// may not share this process's in-memory mutex (e.g. two admin-acl
+	// instances pointed at the same S3 bucket or database). Implementations
+	// that can't offer real distributed locking document what they fall
+	// back to.
+	Lock() error
+	Unlock() error
+}
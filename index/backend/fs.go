@@ -0,0 +1,219 @@
+package backend
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// tmpSuffix marks an in-progress write. A leftover file with this suffix
+// after an unclean shutdown means the write never completed (or completed
+// but the rename didn't land), and is reconciled the next time the backend
+// is opened.
+const tmpSuffix = ".tmp"
+
+// FSBackend stores keys as files on the local filesystem, writing them
+// atomically via a temp-file-then-rename so a crash mid-write can never
+// leave a key's contents truncated.
+//
+// If root is empty, keys are used as-is (so existing absolute or
+// CWD-relative paths behave exactly as they did before this backend
+// existed); otherwise relative keys are joined onto root and absolute keys
+// are still used as-is.
+type FSBackend struct {
+	root string
+	mu   sync.Mutex // serializes Lock()/Unlock() for in-process callers sharing this backend
+}
+
+// NewFSBackend opens an FSBackend rooted at root (or, with root == "",
+// operating on keys as literal filesystem paths), reconciling any leftover
+// ".tmp" files from a prior unclean shutdown.
+func NewFSBackend(root string) (*FSBackend, error) {
+	b := &FSBackend{root: root}
+
+	if root != "" {
+		if err := os.MkdirAll(root, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.reconcileTmpFiles(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func (b *FSBackend) path(key string) string {
+	if b.root == "" || filepath.IsAbs(key) {
+		return key
+	}
+	return filepath.Join(b.root, key)
+}
+
+func (b *FSBackend) Get(key string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+// Put writes data to key, first to a sibling "<key>.tmp" file which is
+// fsynced and renamed into place, with the parent directory fsynced
+// afterwards so the rename itself is durable.
+func (b *FSBackend) Put(key string, data []byte) error {
+	target := b.path(key)
+
+	if dir := filepath.Dir(target); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+
+	tmp := target + tmpSuffix
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	if err := os.Rename(tmp, target); err != nil {
+		if !isCrossDevice(err) {
+			os.Remove(tmp)
+			return err
+		}
+
+		// rename isn't possible across filesystems; fall back to copy+remove
+		if err := copyFile(tmp, target); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		os.Remove(tmp)
+	}
+
+	return fsyncDir(filepath.Dir(target))
+}
+
+func (b *FSBackend) Delete(key string) error {
+	if err := os.Remove(b.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *FSBackend) List() ([]string, error) {
+	if b.root == "" {
+		return nil, errors.New("FSBackend: List requires a non-empty root")
+	}
+
+	var keys []string
+	err := filepath.WalkDir(b.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || strings.HasSuffix(p, tmpSuffix) {
+			return nil
+		}
+		rel, err := filepath.Rel(b.root, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, rel)
+		return nil
+	})
+	return keys, err
+}
+
+// Lock/Unlock only serialize callers within this process; the filesystem
+// offers no distributed locking primitive, so writers on other nodes are not
+// excluded.
+func (b *FSBackend) Lock() error {
+	b.mu.Lock()
+	return nil
+}
+
+func (b *FSBackend) Unlock() error {
+	b.mu.Unlock()
+	return nil
+}
+
+// isCrossDevice reports whether err is the result of attempting a rename
+// across filesystem boundaries (EXDEV), which requires a copy+remove
+// fallback instead.
+func isCrossDevice(err error) bool {
+	var linkErr *os.LinkError
+	return errors.As(err, &linkErr) && errors.Is(linkErr.Err, syscall.EXDEV)
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// fsyncDir fsyncs a directory so that a prior rename into it is durable,
+// not just visible.
+func fsyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// reconcileTmpFiles looks for "*.tmp" leftovers under root from a previous
+// unclean shutdown. If the final file is missing, the tmp file is promoted
+// (the write had completed but the rename hadn't); otherwise the orphaned
+// tmp file is discarded in favor of the existing final file.
+func (b *FSBackend) reconcileTmpFiles() error {
+	if b.root == "" {
+		return nil
+	}
+
+	return filepath.WalkDir(b.root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(p, tmpSuffix) {
+			return nil
+		}
+
+		finalPath := strings.TrimSuffix(p, tmpSuffix)
+		if _, err := os.Stat(finalPath); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+			return os.Rename(p, finalPath)
+		}
+
+		// the final file already exists; the tmp file is a stale leftover
+		return os.Remove(p)
+	})
+}
@@ -0,0 +1,74 @@
+package backend
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFSBackendRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	b, err := NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	if _, err := b.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	if err := b.Put("a/b.hujson", []byte("data")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	data, err := b.Get("a/b.hujson")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(data) != "data" {
+		t.Fatalf("Get = %q, want %q", data, "data")
+	}
+
+	keys, err := b.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != filepath.Join("a", "b.hujson") {
+		t.Fatalf("List = %v, want [%s]", keys, filepath.Join("a", "b.hujson"))
+	}
+
+	if err := b.Delete("a/b.hujson"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := b.Get("a/b.hujson"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+}
+
+// TestFSBackendReconcilesOrphanedTmpFile is a regression test for request
+// #chunk0-3: a ".tmp" file left behind by a crash mid-write (after the
+// rename step, or before it) must be reconciled on the next Open, not left
+// to shadow or corrupt the real key.
+func TestFSBackendReconcilesOrphanedTmpFile(t *testing.T) {
+	dir := t.TempDir()
+
+	// rename hadn't happened yet: promote the tmp file
+	if err := os.WriteFile(filepath.Join(dir, "pending.hujson.tmp"), []byte("finished-write"), 0644); err != nil {
+		t.Fatalf("seeding tmp file: %v", err)
+	}
+
+	b, err := NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	data, err := b.Get("pending.hujson")
+	if err != nil {
+		t.Fatalf("Get(pending.hujson): %v", err)
+	}
+	if string(data) != "finished-write" {
+		t.Fatalf("Get = %q, want %q", data, "finished-write")
+	}
+}
@@ -0,0 +1,199 @@
+package backend
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// GitRemoteBackend stores keys as files in a local clone of a remote git
+// repository, pulling before and pushing after every write (and pulling on
+// Lock) so multiple admin-acl instances can share profile storage without a
+// shared filesystem or database. Get reads the local clone as of the last
+// pull; it does not pull itself.
+type GitRemoteBackend struct {
+	dir    string // local clone directory
+	repo   *git.Repository
+	auth   transport.AuthMethod
+	author object.Signature
+
+	mu sync.Mutex // guards Lock/Unlock's exported critical section (see below)
+
+	// writeMu serializes every Put/Delete's use of repo/Worktree. Unlike
+	// FSBackend/S3Backend/SQLBackend, where each key's storage is
+	// independent, every write here mutates the same in-memory
+	// *git.Repository/Worktree (wt.Add/Commit and repo.Push), which go-git
+	// does not support calling concurrently. index.Index only takes a
+	// per-profile lock before calling Put (see index/index.go's Set/Remove),
+	// so two profiles written at once would otherwise race on that shared
+	// object; writeMu is distinct from mu so it doesn't deadlock against the
+	// explicit Lock/Unlock setIdxData takes around the index.json write.
+	writeMu sync.Mutex
+}
+
+// NewGitRemoteBackend clones (or opens, if already cloned) remoteURL into
+// dir.
+func NewGitRemoteBackend(dir, remoteURL string, auth transport.AuthMethod) (*GitRemoteBackend, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		if !errors.Is(err, git.ErrRepositoryNotExists) {
+			return nil, err
+		}
+		repo, err = git.PlainClone(dir, false, &git.CloneOptions{URL: remoteURL, Auth: auth})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &GitRemoteBackend{
+		dir:  dir,
+		repo: repo,
+		auth: auth,
+		author: object.Signature{
+			Name:  "headscale-admin-acl",
+			Email: "headscale-admin-acl@localhost",
+		},
+	}, nil
+}
+
+func (b *GitRemoteBackend) path(key string) string {
+	return filepath.Join(b.dir, key)
+}
+
+func (b *GitRemoteBackend) Get(key string) ([]byte, error) {
+	data, err := os.ReadFile(b.path(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return data, nil
+}
+
+func (b *GitRemoteBackend) Put(key string, data []byte) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	target := b.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return err
+	}
+
+	return b.commitAndPush(key, "update "+key)
+}
+
+func (b *GitRemoteBackend) Delete(key string) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if err := os.Remove(b.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return b.commitAndPush(key, "delete "+key)
+}
+
+func (b *GitRemoteBackend) List() ([]string, error) {
+	var keys []string
+
+	err := filepath.WalkDir(b.dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(b.dir, p)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, rel)
+		return nil
+	})
+
+	return keys, err
+}
+
+// commitAndPush pulls the latest remote state before committing and
+// pushing, so writes that don't go through Lock/Unlock (every Put/Delete of
+// a profile file, as opposed to setIdxData's index.json writes) still stay
+// fast-forward of the remote instead of routinely hitting non-fast-forward
+// push rejections when two admin-acl instances edit different profiles
+// concurrently.
+func (b *GitRemoteBackend) commitAndPush(key, message string) error {
+	wt, err := b.repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if err := wt.Pull(&git.PullOptions{Auth: b.auth}); err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+
+	if _, err := wt.Add(key); err != nil {
+		return err
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		b.author.When = time.Now()
+		if _, err := wt.Commit(message, &git.CommitOptions{Author: &b.author}); err != nil {
+			return err
+		}
+	}
+
+	err = b.repo.Push(&git.PushOptions{Auth: b.auth})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return err
+	}
+	return nil
+}
+
+// Lock pulls the latest remote state before a critical section, guarded by
+// a mutex distinct from the one Put/Delete use internally (see writeMu) so
+// setIdxData's Lock-then-Put sequence around the index.json write doesn't
+// deadlock against itself. The repo has no server-side locking primitive,
+// so conflicting concurrent writers still need to resolve merge conflicts
+// on Push. If the worktree lookup or pull fails, mu is released before the
+// error is returned - callers only pair a successful Lock with a deferred
+// Unlock, so leaving mu held here would wedge every future
+// Set/Remove/RenameProfile against this backend behind a single failed
+// pull.
+func (b *GitRemoteBackend) Lock() error {
+	b.mu.Lock()
+
+	wt, err := b.repo.Worktree()
+	if err != nil {
+		b.mu.Unlock()
+		return err
+	}
+
+	err = wt.Pull(&git.PullOptions{Auth: b.auth})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		b.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+func (b *GitRemoteBackend) Unlock() error {
+	b.mu.Unlock()
+	return nil
+}
@@ -0,0 +1,99 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Backend stores keys as objects in an S3 (or S3-compatible, e.g. MinIO)
+// bucket, for HA headscale deployments where multiple admin-acl instances
+// need to share the same profile storage without a shared filesystem.
+type S3Backend struct {
+	client *s3.Client
+	bucket string
+	prefix string // optional key prefix, joined with "/"
+}
+
+// NewS3Backend builds an S3Backend that stores objects under prefix in
+// bucket using client.
+func NewS3Backend(client *s3.Client, bucket, prefix string) *S3Backend {
+	return &S3Backend{client: client, bucket: bucket, prefix: prefix}
+}
+
+func (b *S3Backend) objectKey(key string) string {
+	if b.prefix == "" {
+		return key
+	}
+	return b.prefix + "/" + key
+}
+
+func (b *S3Backend) Get(key string) ([]byte, error) {
+	out, err := b.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	if err != nil {
+		var notFound *types.NoSuchKey
+		if errors.As(err, &notFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	defer out.Body.Close()
+
+	return io.ReadAll(out.Body)
+}
+
+func (b *S3Backend) Put(key string, data []byte) error {
+	_, err := b.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(key)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (b *S3Backend) Delete(key string) error {
+	_, err := b.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(b.bucket),
+		Key:    aws.String(b.objectKey(key)),
+	})
+	return err
+}
+
+func (b *S3Backend) List() ([]string, error) {
+	var keys []string
+
+	// list only under "<prefix>/" and strip it, so the returned keys are the
+	// same keys Get/Put/Delete take
+	listPrefix := b.objectKey("")
+
+	paginator := s3.NewListObjectsV2Paginator(b.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(b.bucket),
+		Prefix: aws.String(listPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.ToString(obj.Key), listPrefix))
+		}
+	}
+
+	return keys, nil
+}
+
+// Lock/Unlock are no-ops: S3 has no native cross-client locking primitive.
+// HA deployments that need real mutual exclusion should pair this backend
+// with an external lock (e.g. a DynamoDB lock table) at the call site.
+func (b *S3Backend) Lock() error   { return nil }
+func (b *S3Backend) Unlock() error { return nil }
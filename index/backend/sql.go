@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"errors"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// kvRow is the single table SQLBackend uses to store every key/value pair,
+// regardless of which SQL dialect gorm is configured with (sqlite/postgres/
+// mysql). It isn't named "object" because that collides with the
+// go-git/plumbing/object package imported elsewhere in this package (see
+// gitremote.go).
+type kvRow struct {
+	Key  string `gorm:"primaryKey"`
+	Data []byte
+}
+
+// SQLBackend stores keys as rows in a SQL database via gorm, so profiles can
+// live alongside the rest of an application's data instead of on disk.
+type SQLBackend struct {
+	db *gorm.DB
+	mu sync.Mutex // advisory in-process lock; see Lock/Unlock
+}
+
+// NewSQLBackend migrates the backing table on db and returns a SQLBackend
+// that uses it. db may be opened against sqlite, postgres, or mysql - gorm
+// abstracts the dialect away for our purposes.
+func NewSQLBackend(db *gorm.DB) (*SQLBackend, error) {
+	if err := db.AutoMigrate(&kvRow{}); err != nil {
+		return nil, err
+	}
+	return &SQLBackend{db: db}, nil
+}
+
+func (b *SQLBackend) Get(key string) ([]byte, error) {
+	var row kvRow
+	if err := b.db.First(&row, "key = ?", key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return row.Data, nil
+}
+
+func (b *SQLBackend) Put(key string, data []byte) error {
+	row := kvRow{Key: key, Data: data}
+	return b.db.Save(&row).Error
+}
+
+func (b *SQLBackend) Delete(key string) error {
+	return b.db.Delete(&kvRow{}, "key = ?", key).Error
+}
+
+func (b *SQLBackend) List() ([]string, error) {
+	var rows []kvRow
+	if err := b.db.Select("key").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, len(rows))
+	for i, row := range rows {
+		keys[i] = row.Key
+	}
+	return keys, nil
+}
+
+// Lock/Unlock only serialize callers within this process. Advisory locking
+// across database clients varies too much by dialect (pg_advisory_lock vs
+// GET_LOCK vs SQLite's single-writer model) to offer uniformly here; multi-
+// node deployments should rely on the database's own transaction isolation
+// around Put/Delete instead.
+func (b *SQLBackend) Lock() error {
+	b.mu.Lock()
+	return nil
+}
+
+func (b *SQLBackend) Unlock() error {
+	b.mu.Unlock()
+	return nil
+}
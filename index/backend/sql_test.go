@@ -0,0 +1,62 @@
+package backend
+
+import (
+	"errors"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatalf("opening in-memory sqlite db: %v", err)
+	}
+	return db
+}
+
+func TestSQLBackendRoundTrip(t *testing.T) {
+	b, err := NewSQLBackend(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSQLBackend: %v", err)
+	}
+
+	if _, err := b.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get(missing) error = %v, want ErrNotFound", err)
+	}
+
+	if err := b.Put("profiles/a.hujson", []byte("v1")); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	// Put on an existing key overwrites rather than duplicating the row.
+	if err := b.Put("profiles/a.hujson", []byte("v2")); err != nil {
+		t.Fatalf("Put (overwrite): %v", err)
+	}
+
+	data, err := b.Get("profiles/a.hujson")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if string(data) != "v2" {
+		t.Fatalf("Get = %q, want %q", data, "v2")
+	}
+
+	keys, err := b.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "profiles/a.hujson" {
+		t.Fatalf("List = %v, want [profiles/a.hujson]", keys)
+	}
+
+	if err := b.Delete("profiles/a.hujson"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := b.Get("profiles/a.hujson"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get after Delete error = %v, want ErrNotFound", err)
+	}
+}
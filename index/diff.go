@@ -0,0 +1,298 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+)
+
+// diffContextLines is the number of unchanged lines kept around each change
+// when building a unified diff, matching the conventional default used by
+// tools like `diff -u`.
+const diffContextLines = 3
+
+// DiffHunk is a single unified-diff hunk between the currently applied ACL
+// file and a profile that would replace it.
+type DiffHunk struct {
+	Header string   `json:"header"` // e.g. "@@ -1,3 +1,4 @@"
+	Lines  []string `json:"lines"`  // lines prefixed with ' ', '-' or '+'
+}
+
+// ApplyOptions controls the behavior of ApplyWithOptions.
+type ApplyOptions struct {
+	DryRun bool // validate the profile, but do not write the ACL file; no diff is computed (see DiffApply)
+	Backup bool // keep a timestamped copy of the previous ACL file before overwriting it
+}
+
+// DiffApply returns a unified diff between the currently applied ACL file and
+// the profile that would be applied in its place. It does not modify either
+// file.
+func (idx *Index) DiffApply(ctx context.Context, profileName string) ([]DiffHunk, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	profilePath, err := idx.resolveProfilePath(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	newData, err := idx.backend.Get(profilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	oldData, err := idx.backend.Get(idx.aclFilename)
+	if err != nil {
+		if !errors.Is(err, backend.ErrNotFound) {
+			return nil, err
+		}
+		oldData = nil
+	}
+
+	return unifiedDiff(string(oldData), string(newData)), nil
+}
+
+// backupAclFile copies the current ACL file to a timestamped ".bak" sibling.
+// It is a no-op if the ACL file does not yet exist. The backup name also
+// carries a short checksum of its contents, so two backups taken within the
+// same second (easily hit by automation retrying Apply) don't silently
+// overwrite each other unless their contents are actually identical.
+func (idx *Index) backupAclFile() error {
+	data, err := idx.backend.Get(idx.aclFilename)
+	if err != nil {
+		if errors.Is(err, backend.ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.%s.bak", idx.aclFilename, time.Now().UTC().Format("20060102T150405Z"), checksum(data)[:8])
+	return idx.backend.Put(backupPath, data)
+}
+
+// ApplyWithOptions validates the profile's HuJSON contents and writes them
+// to the ACL file, as Apply does, and additionally supports validating
+// without writing (DryRun) or preserving the previous ACL file (Backup)
+// before it is overwritten.
+func (idx *Index) ApplyWithOptions(ctx context.Context, profileName string, opts ApplyOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	lock := idx.profileLock(profileName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	profilePath, err := idx.resolveProfilePath(profileName)
+	if err != nil {
+		return err
+	}
+
+	data, err := idx.backend.Get(profilePath)
+	if err != nil {
+		return err
+	}
+
+	if err := validateACL(data); err != nil {
+		return err
+	}
+
+	if opts.DryRun {
+		return nil
+	}
+
+	if opts.Backup {
+		if err := idx.backupAclFile(); err != nil {
+			return err
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := idx.setAclData(data); err != nil {
+		return err
+	}
+
+	_, err = idx.history.Note(fmt.Sprintf("apply profile %q", profileName), idx.historyAuthor())
+	return err
+}
+
+// unifiedDiff computes a line-based unified diff between old and new,
+// using a longest-common-subsequence alignment.
+func unifiedDiff(old, new string) []DiffHunk {
+	oldLines := splitLines(old)
+	newLines := splitLines(new)
+
+	ops := diffLines(oldLines, newLines)
+	return buildHunks(ops, oldLines, newLines)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+// opKind identifies whether a diffOp line is unchanged, removed, or added.
+type opKind byte
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+type diffOp struct {
+	kind    opKind
+	oldLine int // index into oldLines, valid for opEqual/opDelete
+	newLine int // index into newLines, valid for opEqual/opInsert
+}
+
+// diffLines aligns oldLines and newLines via their longest common
+// subsequence and returns the resulting sequence of equal/delete/insert
+// operations.
+func diffLines(oldLines, newLines []string) []diffOp {
+	n, m := len(oldLines), len(newLines)
+
+	// lcs[i][j] = length of the LCS of oldLines[i:] and newLines[j:]
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var ops []diffOp
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case oldLines[i] == newLines[j]:
+			ops = append(ops, diffOp{kind: opEqual, oldLine: i, newLine: j})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			ops = append(ops, diffOp{kind: opDelete, oldLine: i})
+			i++
+		default:
+			ops = append(ops, diffOp{kind: opInsert, newLine: j})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		ops = append(ops, diffOp{kind: opDelete, oldLine: i})
+	}
+	for ; j < m; j++ {
+		ops = append(ops, diffOp{kind: opInsert, newLine: j})
+	}
+
+	return ops
+}
+
+// buildHunks groups a flat op sequence into unified-diff hunks, each padded
+// with up to diffContextLines of unchanged context on either side.
+func buildHunks(ops []diffOp, oldLines, newLines []string) []DiffHunk {
+	var hunks []DiffHunk
+
+	for start := 0; start < len(ops); {
+		// find the next changed op
+		for start < len(ops) && ops[start].kind == opEqual {
+			start++
+		}
+		if start >= len(ops) {
+			break
+		}
+
+		end := start
+		for end < len(ops) {
+			// extend the hunk through runs of changes separated by small
+			// gaps of context so nearby changes share one hunk
+			changeEnd := end
+			for changeEnd < len(ops) && ops[changeEnd].kind != opEqual {
+				changeEnd++
+			}
+			end = changeEnd
+
+			gapEnd := end
+			for gapEnd < len(ops) && gapEnd-end < diffContextLines*2 && ops[gapEnd].kind == opEqual {
+				gapEnd++
+			}
+			if gapEnd < len(ops) && ops[gapEnd].kind != opEqual {
+				end = gapEnd
+				continue
+			}
+			break
+		}
+
+		hunkStart := start - diffContextLines
+		if hunkStart < 0 {
+			hunkStart = 0
+		}
+		hunkEnd := end + diffContextLines
+		if hunkEnd > len(ops) {
+			hunkEnd = len(ops)
+		}
+
+		hunks = append(hunks, makeHunk(ops[hunkStart:hunkEnd], oldLines, newLines))
+		start = hunkEnd
+	}
+
+	return hunks
+}
+
+func makeHunk(ops []diffOp, oldLines, newLines []string) DiffHunk {
+	var lines []string
+	var oldStart, newStart = -1, -1
+	var oldCount, newCount int
+
+	for _, op := range ops {
+		switch op.kind {
+		case opEqual:
+			if oldStart == -1 {
+				oldStart = op.oldLine
+			}
+			if newStart == -1 {
+				newStart = op.newLine
+			}
+			oldCount++
+			newCount++
+			lines = append(lines, " "+oldLines[op.oldLine])
+		case opDelete:
+			if oldStart == -1 {
+				oldStart = op.oldLine
+			}
+			oldCount++
+			lines = append(lines, "-"+oldLines[op.oldLine])
+		case opInsert:
+			if newStart == -1 {
+				newStart = op.newLine
+			}
+			newCount++
+			lines = append(lines, "+"+newLines[op.newLine])
+		}
+	}
+	if oldStart == -1 {
+		oldStart = 0
+	}
+	if newStart == -1 {
+		newStart = 0
+	}
+
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart+1, oldCount, newStart+1, newCount)
+	return DiffHunk{Header: header, Lines: lines}
+}
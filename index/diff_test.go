@@ -0,0 +1,130 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+)
+
+func TestDiffApply(t *testing.T) {
+	dir := t.TempDir()
+	aclFile := filepath.Join(dir, "test.hujson")
+	ctx := context.Background()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+	idx, err := CreateNewIndex(dir, aclFile, be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	if err := idx.Set(ctx, "p1", []byte("{}\n")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	// no ACL file applied yet: diff is against empty contents
+	hunks, err := idx.DiffApply(ctx, "p1")
+	if err != nil {
+		t.Fatalf("DiffApply (no prior ACL): %v", err)
+	}
+	if len(hunks) != 1 || len(hunks[0].Lines) != 1 || hunks[0].Lines[0] != "+{}" {
+		t.Fatalf("DiffApply (no prior ACL) = %+v, want a single added line", hunks)
+	}
+
+	if err := idx.ApplyWithOptions(ctx, "p1", ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyWithOptions: %v", err)
+	}
+
+	if err := idx.Set(ctx, "p1", []byte("{\n\t\"groups\": {},\n}\n")); err != nil {
+		t.Fatalf("Set (v2): %v", err)
+	}
+
+	hunks, err = idx.DiffApply(ctx, "p1")
+	if err != nil {
+		t.Fatalf("DiffApply: %v", err)
+	}
+	if len(hunks) != 1 {
+		t.Fatalf("DiffApply = %+v, want exactly one hunk", hunks)
+	}
+	joined := strings.Join(hunks[0].Lines, "\n")
+	if !strings.Contains(joined, "-{}") || !strings.Contains(joined, "+\t\"groups\": {},") {
+		t.Fatalf("DiffApply lines = %q, missing expected -/+ lines", joined)
+	}
+}
+
+func TestApplyWithOptionsDryRunDoesNotWrite(t *testing.T) {
+	dir := t.TempDir()
+	aclFile := filepath.Join(dir, "test.hujson")
+	ctx := context.Background()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+	idx, err := CreateNewIndex(dir, aclFile, be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	if err := idx.Set(ctx, "p1", []byte("{}\n")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if err := idx.ApplyWithOptions(ctx, "p1", ApplyOptions{DryRun: true}); err != nil {
+		t.Fatalf("ApplyWithOptions(DryRun): %v", err)
+	}
+
+	if _, err := be.Get(aclFile); !errors.Is(err, backend.ErrNotFound) {
+		t.Fatalf("Get(aclFile) after DryRun Apply error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestApplyWithOptionsBackup(t *testing.T) {
+	dir := t.TempDir()
+	aclFile := filepath.Join(dir, "test.hujson")
+	ctx := context.Background()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+	idx, err := CreateNewIndex(dir, aclFile, be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	if err := idx.Set(ctx, "p1", []byte("{}\n")); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if err := idx.ApplyWithOptions(ctx, "p1", ApplyOptions{}); err != nil {
+		t.Fatalf("ApplyWithOptions (first): %v", err)
+	}
+
+	if err := idx.Set(ctx, "p1", []byte("{\n\t\"groups\": {},\n}\n")); err != nil {
+		t.Fatalf("Set (v2): %v", err)
+	}
+	if err := idx.ApplyWithOptions(ctx, "p1", ApplyOptions{Backup: true}); err != nil {
+		t.Fatalf("ApplyWithOptions (Backup): %v", err)
+	}
+
+	keys, err := be.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	var backups int
+	for _, k := range keys {
+		if strings.Contains(k, ".bak") {
+			backups++
+		}
+	}
+	if backups != 1 {
+		t.Fatalf("found %d .bak files among %v, want 1", backups, keys)
+	}
+}
+
@@ -4,3 +4,6 @@ import "errors"
 
 var ErrProfileExists = errors.New("profile already exists")
 var ErrProfileNotFound = errors.New("profile does not exist")
+var ErrInvalidACL = errors.New("profile does not contain a valid ACL policy")
+var ErrInvalidProfileName = errors.New("invalid profile name")
+var ErrPathEscape = errors.New("resolved path escapes the profiles directory")
@@ -0,0 +1,86 @@
+package index
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/goodieshq/headscale-admin-acl/index/history"
+)
+
+// profileRelPath resolves the profile's file path relative to the
+// profiles/ directory, which is what the git history is rooted at.
+func (idx *Index) profileRelPath(profileName string) (string, error) {
+	profilePath, err := idx.resolveProfilePath(profileName)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Rel(idx.profileDirPath(), profilePath)
+}
+
+// History returns the commit history of a profile, most recent first.
+func (idx *Index) History(profileName string) ([]history.Revision, error) {
+	rel, err := idx.profileRelPath(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.history.Log(rel)
+}
+
+// Checkout restores a profile's contents to a prior revision by SHA. It
+// goes through the same bookkeeping as Set (profile lock, checksum,
+// index.json) rather than writing the restored blob straight to disk, so a
+// restored profile isn't quarantined as corrupted by quarantineCorrupted on
+// the next restart.
+func (idx *Index) Checkout(profileName, sha string) error {
+	rel, err := idx.profileRelPath(profileName)
+	if err != nil {
+		return err
+	}
+
+	lock := idx.profileLock(profileName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	idx.mu.RLock()
+	info, ok := idx.files[profileName]
+	idx.mu.RUnlock()
+	if !ok {
+		return ErrProfileNotFound
+	}
+
+	contents, err := idx.history.Blob(rel, sha)
+	if err != nil {
+		return err
+	}
+
+	if err := idx.backend.Put(info.Path, contents); err != nil {
+		return err
+	}
+
+	idx.mu.Lock()
+	idx.files[profileName] = IndexFileInfo{
+		Path:     info.Path,
+		Checksum: checksum(contents),
+	}
+	err = idx.setIdxData()
+	idx.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	_, err = idx.history.Commit(fmt.Sprintf("checkout profile %q to %s", profileName, sha), idx.historyAuthor())
+	return err
+}
+
+// Blame returns per-line author and timestamp information for a profile's
+// current contents.
+func (idx *Index) Blame(profileName string) ([]history.BlameLine, error) {
+	rel, err := idx.profileRelPath(profileName)
+	if err != nil {
+		return nil, err
+	}
+
+	return idx.history.Blame(rel)
+}
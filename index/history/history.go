@@ -0,0 +1,261 @@
+// Package history tracks changes made under a directory (the profiles/
+// worktree) in a local git repository, so admins get an auditable trail of
+// who changed which ACL profile and when, without requiring an external git
+// binary. Commits always carry author metadata and are additionally
+// GPG-signed when the Tracker is configured with WithSigningKey.
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// Author identifies who made a change, used for git commit metadata.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// Revision is one historical commit touching the profiles worktree.
+type Revision struct {
+	SHA     string
+	Author  Author
+	When    time.Time
+	Message string
+}
+
+// BlameLine attributes a single line of a file's current contents to the
+// commit that last touched it.
+type BlameLine struct {
+	Line   string
+	SHA    string
+	Author Author
+	When   time.Time
+}
+
+// Tracker commits changes made under a directory into a local git
+// repository.
+type Tracker struct {
+	dir     string
+	repo    *git.Repository
+	signKey *openpgp.Entity
+}
+
+// Option configures a Tracker.
+type Option func(*Tracker)
+
+// WithSigningKey GPG-signs every commit and note Tracker records. Without
+// this option, commits still carry author metadata but are not
+// cryptographically signed. Use LoadSigningKey to build key from an
+// armored private key file.
+func WithSigningKey(key *openpgp.Entity) Option {
+	return func(t *Tracker) { t.signKey = key }
+}
+
+// LoadSigningKey reads an ASCII-armored OpenPGP private key from path and
+// returns the first entity in it, decrypting the private key with
+// passphrase if it is encrypted (pass nil if it isn't). The result is meant
+// to be passed to WithSigningKey.
+func LoadSigningKey(path string, passphrase []byte) (*openpgp.Entity, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	entities, err := openpgp.ReadArmoredKeyRing(f)
+	if err != nil {
+		return nil, err
+	}
+	if len(entities) == 0 {
+		return nil, fmt.Errorf("history: %s contains no OpenPGP entities", path)
+	}
+
+	entity := entities[0]
+	if entity.PrivateKey != nil && entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("history: decrypting signing key: %w", err)
+		}
+	}
+
+	return entity, nil
+}
+
+// Open initializes (or opens, if already present) a git repository rooted at
+// dir.
+func Open(dir string, opts ...Option) (*Tracker, error) {
+	repo, err := git.PlainOpen(dir)
+	if err != nil {
+		if err != git.ErrRepositoryNotExists {
+			return nil, err
+		}
+		if repo, err = git.PlainInit(dir, false); err != nil {
+			return nil, err
+		}
+	}
+
+	t := &Tracker{dir: dir, repo: repo}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t, nil
+}
+
+// Commit stages every change under the tracked directory and records a
+// commit with the given message and author. It is a no-op if nothing
+// changed. If the Tracker was opened with WithSigningKey, the commit is
+// GPG-signed; otherwise it carries only the author metadata above.
+func (t *Tracker) Commit(message string, author Author) (string, error) {
+	wt, err := t.repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := wt.Add("."); err != nil {
+		return "", err
+	}
+
+	status, err := wt.Status()
+	if err != nil {
+		return "", err
+	}
+	if status.IsClean() {
+		return "", nil
+	}
+
+	sig := &object.Signature{Name: author.Name, Email: author.Email, When: time.Now()}
+	hash, err := wt.Commit(message, &git.CommitOptions{Author: sig, SignKey: t.signKey})
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// Note records an audit commit for an event that didn't necessarily change
+// any tracked file (e.g. applying a profile), using AllowEmptyCommits so the
+// history still reflects that the event happened. Like Commit, it is
+// GPG-signed when the Tracker was opened with WithSigningKey.
+func (t *Tracker) Note(message string, author Author) (string, error) {
+	wt, err := t.repo.Worktree()
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := wt.Add("."); err != nil {
+		return "", err
+	}
+
+	sig := &object.Signature{Name: author.Name, Email: author.Email, When: time.Now()}
+	hash, err := wt.Commit(message, &git.CommitOptions{Author: sig, AllowEmptyCommits: true, SignKey: t.signKey})
+	if err != nil {
+		return "", err
+	}
+
+	return hash.String(), nil
+}
+
+// Log returns the commit history that touched path (relative to the tracked
+// directory), most recent first.
+func (t *Tracker) Log(path string) ([]Revision, error) {
+	commitIter, err := t.repo.Log(&git.LogOptions{FileName: &path})
+	if err != nil {
+		return nil, err
+	}
+	defer commitIter.Close()
+
+	var revisions []Revision
+	err = commitIter.ForEach(func(c *object.Commit) error {
+		revisions = append(revisions, Revision{
+			SHA:     c.Hash.String(),
+			Author:  Author{Name: c.Author.Name, Email: c.Author.Email},
+			When:    c.Author.When,
+			Message: c.Message,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// Blob returns path's contents as of the commit sha, read directly out of
+// the commit's tree. Callers that need to restore a prior revision (e.g.
+// Index.Checkout) should write the result back through whatever bookkeeping
+// governs that path rather than writing straight to the worktree, since the
+// tracked directory is not necessarily the only record of that path's
+// contents (see Index.files' checksums).
+func (t *Tracker) Blob(path, sha string) ([]byte, error) {
+	commit, err := t.repo.CommitObject(plumbing.NewHash(sha))
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := commit.File(path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := file.Contents()
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(contents), nil
+}
+
+// Checkout restores path to its contents as of the commit sha by writing
+// directly into the tracked directory. This reads the blob directly out of
+// the commit's tree and overwrites the worktree file, rather than using
+// Worktree.Checkout, which always restores the entire tree and would undo
+// changes to every other profile at once. Prefer Blob when the restored
+// contents need to flow through other bookkeeping (e.g. Index.Checkout).
+func (t *Tracker) Checkout(path, sha string) error {
+	contents, err := t.Blob(path, sha)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(t.dir, path), contents, 0644)
+}
+
+// Blame returns per-line author and timestamp information for the current
+// contents of path, mirroring `git blame`.
+func (t *Tracker) Blame(path string) ([]BlameLine, error) {
+	head, err := t.repo.Head()
+	if err != nil {
+		return nil, err
+	}
+
+	commit, err := t.repo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := git.Blame(commit, path)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := make([]BlameLine, 0, len(result.Lines))
+	for _, l := range result.Lines {
+		lines = append(lines, BlameLine{
+			Line:   l.Text,
+			SHA:    l.Hash.String(),
+			Author: Author{Name: l.Author},
+			When:   l.Date,
+		})
+	}
+
+	return lines, nil
+}
@@ -0,0 +1,96 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+)
+
+// TestCheckoutSurvivesRestart is a regression test for request #chunk0-2:
+// Checkout must update Index's checksum bookkeeping along with the on-disk
+// contents, not just write the restored blob straight to the worktree.
+// Otherwise the next initializeIdx sees a checksum mismatch and
+// quarantineCorrupted deletes the profile it just restored.
+func TestCheckoutSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	aclFile := filepath.Join(dir, "test.hujson")
+	ctx := context.Background()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	idx, err := CreateNewIndex(dir, aclFile, be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	if err := idx.Set(ctx, "p1", []byte("v1")); err != nil {
+		t.Fatalf("Set(v1): %v", err)
+	}
+	if err := idx.Set(ctx, "p1", []byte("v2")); err != nil {
+		t.Fatalf("Set(v2): %v", err)
+	}
+
+	revisions, err := idx.History("p1")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(revisions) != 2 {
+		t.Fatalf("History returned %d revisions, want 2", len(revisions))
+	}
+	v1SHA := revisions[len(revisions)-1].SHA
+
+	if err := idx.Checkout("p1", v1SHA); err != nil {
+		t.Fatalf("Checkout: %v", err)
+	}
+
+	data, err := idx.Get(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Get after Checkout: %v", err)
+	}
+	if string(data) != "v1" {
+		t.Fatalf("Get after Checkout = %q, want %q", data, "v1")
+	}
+
+	// Reopen the index, as a fresh process would on restart, and confirm
+	// quarantineCorrupted doesn't see a stale checksum and delete "p1".
+	be2, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend (reopen): %v", err)
+	}
+	idx2, err := CreateNewIndex(dir, aclFile, be2, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex (reopen): %v", err)
+	}
+
+	data, err = idx2.Get(ctx, "p1")
+	if err != nil {
+		t.Fatalf("Get after restart: %v", err)
+	}
+	if string(data) != "v1" {
+		t.Fatalf("Get after restart = %q, want %q", data, "v1")
+	}
+}
+
+func TestCheckoutUnknownProfile(t *testing.T) {
+	dir := t.TempDir()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	idx, err := CreateNewIndex(dir, filepath.Join(dir, "test.hujson"), be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	if err := idx.Checkout("does-not-exist", "deadbeef"); !errors.Is(err, ErrProfileNotFound) {
+		t.Fatalf("Checkout(does-not-exist) error = %v, want ErrProfileNotFound", err)
+	}
+}
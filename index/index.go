@@ -1,27 +1,74 @@
 package index
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"io/fs"
-	"os"
+	"os/user"
 	"path"
 	"sync"
 
-	"github.com/google/uuid"
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+	"github.com/goodieshq/headscale-admin-acl/index/history"
 )
 
 type IndexFileInfo struct {
-	Path string `json:"path"`
+	Path     string `json:"path"`
+	Checksum string `json:"checksum"` // sha256 of the profile's contents, hex-encoded
+}
+
+// checksum returns the hex-encoded sha256 of data, used to detect profile
+// files that were corrupted or modified outside of this package.
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 // Main index to keep track of files by a profile name
 type Index struct {
 	idxDir      string // index JSON filename
 	aclFilename string // target ACL filename to update
-	mu          sync.Mutex
-	files       map[string]IndexFileInfo // key is the profile name, value is the information of the file
+
+	mu    sync.RWMutex             // guards files
+	files map[string]IndexFileInfo // key is the profile name, value is the information of the file
+
+	profileLocksMu sync.Mutex
+	profileLocks   map[string]*sync.Mutex // serializes writes to the same profile's on-disk contents
+
+	idxFileMu sync.Mutex // serializes writes to the on-disk index file itself
+
+	backend backend.Backend  // where profiles, the index, and the ACL file are actually stored
+	history *history.Tracker // git-backed history of profiles/ changes
+
+	nameValidator *NameValidator // bounds the profile names Set/RenameProfile accept
+}
+
+// profileLock returns the mutex used to serialize on-disk writes to a
+// single profile, creating one on first use. Distinct profiles get distinct
+// mutexes, so e.g. Set("a") never blocks on Set("b").
+func (idx *Index) profileLock(profileName string) *sync.Mutex {
+	idx.profileLocksMu.Lock()
+	defer idx.profileLocksMu.Unlock()
+
+	l, ok := idx.profileLocks[profileName]
+	if !ok {
+		l = &sync.Mutex{}
+		idx.profileLocks[profileName] = l
+	}
+	return l
+}
+
+// historyAuthor builds the author metadata attached to each history commit,
+// falling back to a generic identity if the current OS user can't be
+// determined.
+func (idx *Index) historyAuthor() history.Author {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return history.Author{Name: u.Username, Email: u.Username + "@localhost"}
+	}
+	return history.Author{Name: "headscale-admin-acl", Email: "headscale-admin-acl@localhost"}
 }
 
 func (idx *Index) profileDirPath() string {
@@ -34,193 +81,209 @@ func (idx *Index) profileIndexPath() string {
 	return path.Join(idx.profileDirPath(), "index.json")
 }
 
-// create or ensure the existence of the Index directory
-func (idx *Index) createIdxDir() error {
-	var stat fs.FileInfo
-	var err error
-
-	idx.mu.Lock()
-	defer idx.mu.Unlock()
-
-	// check if the idxDir base directory exists
-	stat, err = os.Stat(idx.idxDir)
+func (idx *Index) initializeIdx() error {
+	data, err := idx.backend.Get(idx.profileIndexPath())
 	if err != nil {
-		if !os.IsNotExist(err) {
+		if !errors.Is(err, backend.ErrNotFound) {
 			return err
 		}
 
-		// create the directory if it does not exist
-		if err = os.MkdirAll(idx.idxDir, 0755); err != nil {
-			return err
-		}
-
-		// get the updated state of the newly created directory
-		stat, err = os.Stat(idx.idxDir)
-		if err != nil {
-			return err
-		}
-	}
-
-	// stat should be populated at this point. ensure it is a directory
-	if !stat.IsDir() {
-		return fmt.Errorf("idxDir '%s' is not a valid directory", idx.idxDir)
+		// index doesn't exist yet; create it with empty data
+		return idx.setIdxData()
 	}
 
-	// and ensure the directory is writable
-	if stat.Mode().Perm()&0200 == 0 {
-		return fmt.Errorf("idxDir '%s' is not writable", idx.idxDir)
-	}
-
-	// create the profile directory if it does not exist
-	if err = os.MkdirAll(idx.profileDirPath(), 0755); err != nil {
+	if err = json.Unmarshal(data, &idx.files); err != nil {
 		return err
 	}
 
-	return nil
+	return idx.quarantineCorrupted()
 }
 
-func (idx *Index) initializeIdx() error {
-	var err error
-
-	// ensure directory is created and valid
-	if err = idx.createIdxDir(); err != nil {
-		return err
-	}
+// quarantineCorrupted checks every known profile's stored checksum against
+// the checksum recorded in the index, and moves aside (rather than loads)
+// any profile whose contents were corrupted or externally modified.
+func (idx *Index) quarantineCorrupted() error {
+	for profileName, info := range idx.files {
+		if info.Checksum == "" {
+			// no checksum recorded (e.g. profile predates this field); skip
+			continue
+		}
 
-	// check if the index file exists
-	_, err = os.Stat(idx.profileIndexPath())
-	if err != nil {
-		// if the index JSON file does not exist, create it with empty data
-		if !os.IsNotExist(err) {
-			return err
+		if err := idx.ensureWithinProfileDir(info.Path); err != nil {
+			delete(idx.files, profileName)
+			continue
 		}
 
-		if err = idx.setIdxData(); err != nil {
+		data, err := idx.backend.Get(info.Path)
+		if err != nil {
+			if errors.Is(err, backend.ErrNotFound) {
+				delete(idx.files, profileName)
+				continue
+			}
 			return err
 		}
-	} else {
-		// index file exists. load the contents
-		if data, err := os.ReadFile(idx.profileIndexPath()); err != nil {
-			return err
-		} else {
-			return json.Unmarshal(data, &idx.files)
+
+		if checksum(data) != info.Checksum {
+			if err := idx.backend.Put(info.Path+".corrupted", data); err != nil {
+				return err
+			}
+			if err := idx.backend.Delete(info.Path); err != nil {
+				return err
+			}
+			delete(idx.files, profileName)
 		}
 	}
 
-	return nil
+	return idx.setIdxData()
 }
 
 // sets the data of the ACL file
 func (idx *Index) setAclData(data []byte) error {
-	var err error
+	return idx.backend.Put(idx.aclFilename, data)
+}
+
+// Save the index. Callers must hold idx.mu (for the read of idx.files);
+// writes to the on-disk file are further serialized by idxFileMu so two
+// profiles' Set calls can't interleave their index.json writes. This is
+// also the only place that takes the backend-wide Lock/Unlock: it's the one
+// piece of state every profile's Set/Remove/RenameProfile shares, so it
+// needs cross-backend mutual exclusion (e.g. against another admin-acl
+// instance pointed at the same S3 bucket); the profile files themselves do
+// not, and are left to profileLock so they don't serialize on each other.
+func (idx *Index) setIdxData() error {
+	idx.idxFileMu.Lock()
+	defer idx.idxFileMu.Unlock()
 
-	f, err := os.Create(idx.aclFilename)
+	data, err := json.MarshalIndent(idx.files, "", "    ")
 	if err != nil {
 		return err
 	}
-	defer f.Close()
 
-	_, err = f.Write(data)
-	if err != nil {
+	if err := idx.backend.Lock(); err != nil {
 		return err
 	}
+	defer idx.backend.Unlock()
 
-	return nil
+	return idx.backend.Put(idx.profileIndexPath(), data)
 }
 
-// Save the index
-func (idx *Index) setIdxData() error {
-	var err error
+// Get returns the raw contents of a profile.
+func (idx *Index) Get(ctx context.Context, profileName string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	f, err := os.Create(idx.profileIndexPath())
+	profilePath, err := idx.resolveProfilePath(profileName)
 	if err != nil {
+		return nil, err
+	}
+
+	return idx.backend.Get(profilePath)
+}
+
+func (idx *Index) Remove(ctx context.Context, profileName string) error {
+	if err := ctx.Err(); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	data, err := json.MarshalIndent(idx.files, "", "    ")
+	lock := idx.profileLock(profileName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	idx.mu.Lock()
+	delete(idx.files, profileName)
+	err := idx.setIdxData()
+	idx.mu.Unlock()
 	if err != nil {
 		return err
 	}
 
-	_, err = f.Write(data)
+	_, err = idx.history.Commit(fmt.Sprintf("remove profile %q", profileName), idx.historyAuthor())
 	return err
 }
-func (idx *Index) Remove(profileName string) error {
-	idx.mu.Lock()
-	defer idx.mu.Unlock()
-
-	delete(idx.files, profileName)
-	return idx.setIdxData()
-}
 
 // assign data to a new or existing profile
-func (idx *Index) Set(profileName string, profileData []byte) error {
-	var err error
-	var profilePath string
-	var isNew bool = false
+func (idx *Index) Set(ctx context.Context, profileName string, profileData []byte) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-	idx.mu.Lock()
-	defer idx.mu.Unlock()
+	if err := idx.nameValidator.Validate(profileName); err != nil {
+		return err
+	}
 
-	if info, ok := idx.files[profileName]; ok {
+	lock := idx.profileLock(profileName)
+	lock.Lock()
+	defer lock.Unlock()
+
+	idx.mu.RLock()
+	info, ok := idx.files[profileName]
+	idx.mu.RUnlock()
+
+	var profilePath string
+	if ok {
 		// take the existing path
 		profilePath = info.Path
 	} else {
-		// generate a new UUIDv4
-		id, err := uuid.NewRandom()
-		if err != nil {
-			return err
-		}
-
-		profilePath = path.Join(idx.profileDirPath(), fmt.Sprintf("%s.hujson", id.String()))
-		isNew = true
+		profilePath = path.Join(idx.profileDirPath(), fmt.Sprintf("%s.hujson", MakeFileSafeName(profileName)))
 	}
 
-	f, err := os.Create(profilePath)
-	if err != nil {
+	if err := idx.ensureWithinProfileDir(profilePath); err != nil {
 		return err
 	}
-	defer f.Close()
 
-	_, err = f.Write(profileData)
-	if err != nil {
-		os.Remove(profilePath)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := idx.backend.Put(profilePath, profileData); err != nil {
 		return err
 	}
 
-	if isNew {
-		idx.files[profileName] = IndexFileInfo{
-			Path: profilePath,
-		}
+	idx.mu.Lock()
+	idx.files[profileName] = IndexFileInfo{
+		Path:     profilePath,
+		Checksum: checksum(profileData),
+	}
+	err := idx.setIdxData()
+	idx.mu.Unlock()
+	if err != nil {
+		return err
 	}
 
-	return idx.setIdxData()
+	_, err = idx.history.Commit(fmt.Sprintf("set profile %q", profileName), idx.historyAuthor())
+	return err
 }
 
-func (idx *Index) Apply(profileName string) error {
-	idx.mu.Lock()
-	defer idx.mu.Unlock()
-
-	if info, ok := idx.files[profileName]; ok {
-		f, err := os.Open(info.Path)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
+// Apply writes the profile's contents to the ACL file, rejecting profiles
+// that don't parse as valid HuJSON. See ApplyWithOptions for a dry-run /
+// backup variant.
+func (idx *Index) Apply(ctx context.Context, profileName string) error {
+	return idx.ApplyWithOptions(ctx, profileName, ApplyOptions{})
+}
 
-		data, err := io.ReadAll(f)
-		if err != nil {
-			return err
-		}
+func (idx *Index) RenameProfile(ctx context.Context, profileNameOld, profileNameNew string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
-		return idx.setAclData(data)
+	if err := idx.nameValidator.Validate(profileNameNew); err != nil {
+		return err
 	}
 
-	return ErrProfileNotFound
-}
+	// lock both profile names, always in a stable order, to avoid deadlocking
+	// against a concurrent rename of the same two names in reverse
+	first, second := profileNameOld, profileNameNew
+	if second < first {
+		first, second = second, first
+	}
+	lockFirst, lockSecond := idx.profileLock(first), idx.profileLock(second)
+	lockFirst.Lock()
+	defer lockFirst.Unlock()
+	if lockSecond != lockFirst {
+		lockSecond.Lock()
+		defer lockSecond.Unlock()
+	}
 
-func (idx *Index) RenameProfile(profileNameOld, profileNameNew string) error {
 	idx.mu.Lock()
 	defer idx.mu.Unlock()
 
@@ -236,24 +299,47 @@ func (idx *Index) RenameProfile(profileNameOld, profileNameNew string) error {
 
 	idx.files[profileNameNew] = idx.files[profileNameOld]
 	delete(idx.files, profileNameOld)
-	return idx.setIdxData()
+	if err := idx.setIdxData(); err != nil {
+		return err
+	}
+
+	_, err := idx.history.Commit(fmt.Sprintf("rename profile %q to %q", profileNameOld, profileNameNew), idx.historyAuthor())
+	return err
 }
 
-// Create a new index container for keeping track of files
-func CreateNewIndex(idxDir, aclFilename string) (*Index, error) {
+// Create a new index container for keeping track of files, persisted
+// through be. nameValidator bounds the profile names Set/RenameProfile will
+// accept; pass nil to use DefaultNameValidator. historyOpts are passed
+// through to history.Open; pass history.WithSigningKey to GPG-sign every
+// history commit.
+func CreateNewIndex(idxDir, aclFilename string, be backend.Backend, nameValidator *NameValidator, historyOpts ...history.Option) (*Index, error) {
 	var err error
 
+	if nameValidator == nil {
+		nameValidator = DefaultNameValidator
+	}
+
 	idx := &Index{
-		idxDir:      idxDir,
-		aclFilename: aclFilename,
-		mu:          sync.Mutex{},
-		files:       make(map[string]IndexFileInfo),
+		idxDir:        idxDir,
+		aclFilename:   aclFilename,
+		files:         make(map[string]IndexFileInfo),
+		profileLocks:  make(map[string]*sync.Mutex),
+		backend:       be,
+		nameValidator: nameValidator,
 	}
 
-	// create the directory if necessary
+	// load (or create) the index
 	if err = idx.initializeIdx(); err != nil {
 		return nil, err
 	}
 
+	// track profiles/ in a git worktree so changes are auditable. This
+	// assumes profiles/ is a real local directory, which holds for
+	// backend.FSBackend; History/Checkout/Blame aren't meaningful on top
+	// of the other Backend implementations yet.
+	if idx.history, err = history.Open(idx.profileDirPath(), historyOpts...); err != nil {
+		return nil, err
+	}
+
 	return idx, nil
 }
@@ -0,0 +1,86 @@
+package index
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+)
+
+// gatedBackend blocks every Put to gateKey until release is closed, so a
+// test can hold one profile's write open while asserting a concurrent
+// write to a different profile isn't stuck behind it.
+type gatedBackend struct {
+	backend.Backend
+	gateKey string
+	gate    chan struct{}
+	blocked chan struct{}
+}
+
+func (b *gatedBackend) Put(key string, data []byte) error {
+	if key == b.gateKey {
+		close(b.blocked)
+		<-b.gate
+	}
+	return b.Backend.Put(key, data)
+}
+
+// TestSetDoesNotSerializeAcrossProfiles is a regression test for request
+// #chunk0-6: Set for one profile must not block Set for a different
+// profile, even while the first is still mid-write.
+func TestSetDoesNotSerializeAcrossProfiles(t *testing.T) {
+	dir := t.TempDir()
+
+	fsBackend, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	idx, err := CreateNewIndex(dir, filepath.Join(dir, "test.hujson"), fsBackend, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	aPath := path.Join(idx.profileDirPath(), fmt.Sprintf("%s.hujson", MakeFileSafeName("a")))
+	gated := &gatedBackend{
+		Backend: fsBackend,
+		gateKey: aPath,
+		gate:    make(chan struct{}),
+		blocked: make(chan struct{}),
+	}
+	idx.backend = gated
+
+	ctx := context.Background()
+
+	done := make(chan error, 1)
+	go func() { done <- idx.Set(ctx, "a", []byte("a-data")) }()
+
+	select {
+	case <-gated.blocked:
+	case <-time.After(time.Second):
+		t.Fatal("Set(a) never reached the gated write")
+	}
+
+	// With Set(a) still blocked mid-write, Set(b) must complete promptly
+	// instead of waiting behind a backend-wide lock.
+	bDone := make(chan error, 1)
+	go func() { bDone <- idx.Set(ctx, "b", []byte("b-data")) }()
+
+	select {
+	case err := <-bDone:
+		if err != nil {
+			t.Fatalf("Set(b): %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Set(b) blocked behind Set(a)'s in-flight write")
+	}
+
+	close(gated.gate)
+	if err := <-done; err != nil {
+		t.Fatalf("Set(a): %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+package index
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// NameValidator bounds the profile names Set/RenameProfile accept. Profile
+// names flow into the JSON index and, through MakeFileSafeName, into the
+// names of new profile files, so they are kept free of "/", "..", control
+// characters, and lengths too long for the filesystem.
+type NameValidator struct {
+	Regex     *regexp.Regexp
+	MaxLength int
+}
+
+// DefaultNameValidator allows the common case: a short identifier made of
+// letters, digits, and "._-", which is safe to embed in a filename or URL
+// path segment without escaping.
+var DefaultNameValidator = &NameValidator{
+	Regex:     regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`),
+	MaxLength: 128,
+}
+
+// Validate reports whether name is an acceptable profile name, using v's
+// rules or DefaultNameValidator's if v is nil.
+func (v *NameValidator) Validate(name string) error {
+	if v == nil {
+		v = DefaultNameValidator
+	}
+
+	if len(name) == 0 || len(name) > v.MaxLength {
+		return fmt.Errorf("%w: %q is not between 1 and %d characters", ErrInvalidProfileName, name, v.MaxLength)
+	}
+	if !v.Regex.MatchString(name) {
+		return fmt.Errorf("%w: %q does not match %s", ErrInvalidProfileName, name, v.Regex.String())
+	}
+
+	return nil
+}
+
+// MakeFileSafeName turns an arbitrary profile name into a string that's safe
+// to use as a filename: unsafe runes are replaced, the result is truncated,
+// and a short hash of the original name is appended so that two names which
+// collide after sanitization (e.g. "a/b" and "a_b") don't collide on disk.
+func MakeFileSafeName(profile string) string {
+	var b strings.Builder
+	for _, r := range profile {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+
+	safe := b.String()
+	const maxSafeLen = 64
+	if len(safe) > maxSafeLen {
+		safe = safe[:maxSafeLen]
+	}
+
+	sum := sha256.Sum256([]byte(profile))
+	return fmt.Sprintf("%s-%s", safe, hex.EncodeToString(sum[:])[:8])
+}
+
+// ensureWithinProfileDir guards against a symlink or a tampered index
+// pointing a profile's recorded path outside profileDirPath(), before that
+// path is ever handed to the backend.
+func (idx *Index) ensureWithinProfileDir(p string) error {
+	base, err := filepath.Abs(idx.profileDirPath())
+	if err != nil {
+		return err
+	}
+
+	target, err := filepath.Abs(p)
+	if err != nil {
+		return err
+	}
+
+	// resolve symlinks where the paths exist (for a file not written yet, its
+	// directory), so a symlink can't carry the target out of base
+	if resolved, err := filepath.EvalSymlinks(base); err == nil {
+		base = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil {
+		target = resolved
+	} else if dir, err := filepath.EvalSymlinks(filepath.Dir(target)); err == nil {
+		target = filepath.Join(dir, filepath.Base(target))
+	}
+
+	rel, err := filepath.Rel(base, target)
+	if err != nil {
+		return err
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: %s", ErrPathEscape, p)
+	}
+
+	return nil
+}
+
+// resolveProfilePath looks up profileName's on-disk path and confirms it
+// still resolves within profileDirPath() before returning it.
+func (idx *Index) resolveProfilePath(profileName string) (string, error) {
+	idx.mu.RLock()
+	info, ok := idx.files[profileName]
+	idx.mu.RUnlock()
+
+	if !ok {
+		return "", ErrProfileNotFound
+	}
+
+	if err := idx.ensureWithinProfileDir(info.Path); err != nil {
+		return "", err
+	}
+
+	return info.Path, nil
+}
@@ -0,0 +1,79 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+)
+
+func TestMakeFileSafeNameAvoidsCollisions(t *testing.T) {
+	a := MakeFileSafeName("a/b")
+	b := MakeFileSafeName("a_b")
+	if a == b {
+		t.Fatalf("MakeFileSafeName(%q) and MakeFileSafeName(%q) both produced %q", "a/b", "a_b", a)
+	}
+}
+
+func TestNameValidatorValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"", true},
+		{"valid-name.1", false},
+		{"../escape", true},
+		{"has/slash", true},
+		{strings.Repeat("x", 200), true},
+	}
+
+	for _, c := range cases {
+		err := DefaultNameValidator.Validate(c.name)
+		if (err != nil) != c.wantErr {
+			t.Errorf("Validate(%q) error = %v, wantErr %v", c.name, err, c.wantErr)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidProfileName) {
+			t.Errorf("Validate(%q) error = %v, want it to wrap ErrInvalidProfileName", c.name, err)
+		}
+	}
+}
+
+func TestSetRejectsInvalidProfileName(t *testing.T) {
+	dir := t.TempDir()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	idx, err := CreateNewIndex(dir, filepath.Join(dir, "test.hujson"), be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	if err := idx.Set(context.Background(), "../escape", []byte("data")); !errors.Is(err, ErrInvalidProfileName) {
+		t.Fatalf("Set(%q) error = %v, want ErrInvalidProfileName", "../escape", err)
+	}
+}
+
+func TestEnsureWithinProfileDirRejectsEscape(t *testing.T) {
+	dir := t.TempDir()
+
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+
+	idx, err := CreateNewIndex(dir, filepath.Join(dir, "test.hujson"), be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+
+	escaped := filepath.Join(idx.profileDirPath(), "..", "..", "outside.hujson")
+	if err := idx.ensureWithinProfileDir(escaped); !errors.Is(err, ErrPathEscape) {
+		t.Fatalf("ensureWithinProfileDir(%q) = %v, want ErrPathEscape", escaped, err)
+	}
+}
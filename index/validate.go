@@ -0,0 +1,53 @@
+package index
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tailscale/hujson"
+)
+
+// aclPolicy mirrors the top-level shape of a Tailscale/headscale ACL policy
+// file. We only care that the fields we know about decode cleanly; unknown
+// fields are preserved by the surrounding HuJSON -> JSON standardization step
+// and are not our concern to validate here.
+type aclPolicy struct {
+	Groups        map[string][]string `json:"groups,omitempty"`
+	TagOwners     map[string][]string `json:"tagOwners,omitempty"`
+	ACLs          []json.RawMessage   `json:"acls,omitempty"`
+	SSH           []json.RawMessage   `json:"ssh,omitempty"`
+	AutoApprovers json.RawMessage     `json:"autoApprovers,omitempty"`
+}
+
+// validateACL checks that data parses as HuJSON and that the standardized
+// JSON decodes into the shape of an ACL policy. It does not attempt to
+// validate the semantics of individual rules, just the grammar.
+func validateACL(data []byte) error {
+	std, err := hujson.Standardize(data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidACL, err)
+	}
+
+	var policy aclPolicy
+	if err := json.Unmarshal(std, &policy); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidACL, err)
+	}
+
+	return nil
+}
+
+// Validate checks that the profile with the given name currently contains a
+// syntactically valid HuJSON ACL policy, without applying it.
+func (idx *Index) Validate(profileName string) error {
+	profilePath, err := idx.resolveProfilePath(profileName)
+	if err != nil {
+		return err
+	}
+
+	data, err := idx.backend.Get(profilePath)
+	if err != nil {
+		return err
+	}
+
+	return validateACL(data)
+}
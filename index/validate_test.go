@@ -0,0 +1,52 @@
+package index
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateACL(t *testing.T) {
+	cases := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			data: `{
+				"groups": {"group:admins": ["alice@"]},
+				"tagOwners": {"tag:server": ["group:admins"]},
+				"acls": [{"action": "accept", "src": ["*"], "dst": ["*:*"]}],
+			}`,
+		},
+		{
+			name: "valid with comments and trailing commas (HuJSON)",
+			data: `{
+				// only admins own this tag
+				"tagOwners": {"tag:server": ["group:admins"]},
+			}`,
+		},
+		{
+			name:    "malformed HuJSON",
+			data:    `{"acls": [`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			data:    `["acls"]`,
+			wantErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateACL([]byte(c.data))
+			if (err != nil) != c.wantErr {
+				t.Fatalf("validateACL(%q) error = %v, wantErr %v", c.data, err, c.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidACL) {
+				t.Fatalf("validateACL(%q) error = %v, want it to wrap ErrInvalidACL", c.data, err)
+			}
+		})
+	}
+}
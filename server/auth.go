@@ -0,0 +1,33 @@
+package server
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// validBearerToken reports whether header is a well-formed
+// "Authorization: Bearer <token>" header carrying exactly token, shared by
+// requireToken (HTTP) and tokenInterceptor (gRPC) so both auth surfaces
+// check the same thing the same way.
+func validBearerToken(token, header string) bool {
+	const prefix = "Bearer "
+
+	if !strings.HasPrefix(header, prefix) {
+		return false
+	}
+	got := strings.TrimPrefix(header, prefix)
+	return subtle.ConstantTimeCompare([]byte(got), []byte(token)) == 1
+}
+
+// requireToken wraps next with bearer-token authentication. Requests without
+// a matching "Authorization: Bearer <token>" header are rejected with 401.
+func requireToken(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validBearerToken(token, r.Header.Get("Authorization")) {
+			http.Error(w, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
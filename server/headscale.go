@@ -0,0 +1,84 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"syscall"
+)
+
+// Reloader tells a running headscale instance to pick up a freshly applied
+// ACL file, so Apply doesn't merely write the file and leave the server
+// running on a stale policy. data is the ACL contents that were just
+// applied, for Reloader implementations (like HeadscaleAPIReloader) that
+// push the policy to headscale rather than relying on it to reread a
+// shared file.
+type Reloader interface {
+	Reload(data []byte) error
+}
+
+// SignalReloader reloads headscale by sending it SIGHUP, which is what
+// headscale itself watches for to reread its ACL policy file from disk.
+type SignalReloader struct {
+	Pid int
+}
+
+func (r SignalReloader) Reload([]byte) error {
+	proc, err := os.FindProcess(r.Pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(syscall.SIGHUP)
+}
+
+// HeadscaleAPIReloader reloads headscale via its REST admin API, for
+// deployments where this tool doesn't share a process namespace (or a
+// filesystem) with headscale, e.g. it runs in a different container.
+//
+// headscale has no standalone "reload" endpoint: its policy API is
+// GetPolicy/SetPolicy (gRPC), exposed over REST as GET/PUT
+// /api/v1/policy. Reload therefore PUTs the just-applied ACL contents as
+// the new policy - that call is itself what makes headscale pick it up.
+type HeadscaleAPIReloader struct {
+	BaseURL    string // e.g. "https://headscale.internal:443"
+	APIKey     string
+	HTTPClient *http.Client
+}
+
+// setPolicyRequest mirrors headscale's PUT /api/v1/policy request body.
+type setPolicyRequest struct {
+	Policy string `json:"policy"`
+}
+
+func (r HeadscaleAPIReloader) Reload(data []byte) error {
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	body, err := json.Marshal(setPolicyRequest{Policy: string(data)})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, r.BaseURL+"/api/v1/policy", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+r.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("headscale SetPolicy failed: %s", resp.Status)
+	}
+
+	return nil
+}
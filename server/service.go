@@ -0,0 +1,242 @@
+// Package server wraps index.Index in both an HTTP API and a gRPC API, so
+// profile management doesn't require shelling out to the CLI tool. It
+// optionally hot-reloads a running headscale instance after Apply via a
+// Reloader. See cmd/admin-acl-server for the daemon that serves both:
+// Service.Handler for HTTP, Service.GRPCServer for gRPC.
+//
+// The gRPC service (see grpc.go) is hand-written against grpc-go's
+// ServiceDesc/MethodDesc types rather than generated from a .proto, since no
+// protoc toolchain is available in this environment; it uses a small JSON
+// codec (registered under the "json" subtype) instead of protobuf-encoded
+// messages. A client needs to dial with that subtype (e.g.
+// grpc.CallContentSubtype("json") per call, or a matching custom codec) -
+// this is not wire-compatible with a protoc-generated protobuf client. A
+// follow-up replacing the hand-written messages with a real .proto and
+// protoc-gen-go/protoc-gen-go-grpc stubs, once protoc is available, can
+// swap the codec and message types without touching callers of idx.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/goodieshq/headscale-admin-acl/index"
+	"github.com/rs/zerolog/log"
+)
+
+// Service exposes index.Index over HTTP.
+type Service struct {
+	idx      *index.Index
+	token    string
+	reloader Reloader
+}
+
+// Option configures a Service.
+type Option func(*Service)
+
+// WithToken requires a matching bearer token on every request.
+func WithToken(token string) Option {
+	return func(s *Service) { s.token = token }
+}
+
+// WithReloader hot-reloads a running headscale instance after every
+// successful Apply.
+func WithReloader(r Reloader) Option {
+	return func(s *Service) { s.reloader = r }
+}
+
+// New builds a Service backed by idx.
+func New(idx *index.Index, opts ...Option) *Service {
+	s := &Service{idx: idx}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Handler returns the http.Handler for the service's routes, ready to pass
+// to http.Serve or http.ListenAndServe.
+func (s *Service) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/profiles/", s.handleProfiles)
+
+	var h http.Handler = mux
+	if s.token != "" {
+		h = requireToken(s.token, h)
+	}
+	return h
+}
+
+// handleProfiles dispatches the /profiles/{name}[/action] routes. A single
+// handler (rather than one ServeMux pattern per route) keeps path-parameter
+// extraction in one place without pulling in a router dependency.
+func (s *Service) handleProfiles(w http.ResponseWriter, r *http.Request) {
+	rest := strings.TrimPrefix(r.URL.Path, "/profiles/")
+	name, action, _ := strings.Cut(rest, "/")
+	if name == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case action == "" && r.Method == http.MethodGet:
+		s.getProfile(w, r, name)
+	case action == "" && r.Method == http.MethodPut:
+		s.putProfile(w, r, name)
+	case action == "" && r.Method == http.MethodDelete:
+		s.deleteProfile(w, r, name)
+	case action == "apply" && r.Method == http.MethodPost:
+		s.applyProfile(w, r, name)
+	case action == "rename" && r.Method == http.MethodPost:
+		s.renameProfile(w, r, name)
+	case action == "diff" && r.Method == http.MethodGet:
+		s.diffProfile(w, r, name)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Service) getProfile(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := s.idx.Get(r.Context(), name)
+	if writeIndexError(w, err) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Write(data)
+}
+
+func (s *Service) putProfile(w http.ResponseWriter, r *http.Request, name string) {
+	data, err := readBody(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.idx.Set(r.Context(), name, data); err != nil {
+		writeIndexError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) deleteProfile(w http.ResponseWriter, r *http.Request, name string) {
+	if err := s.idx.Remove(r.Context(), name); writeIndexError(w, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type applyRequest struct {
+	DryRun bool `json:"dryRun"`
+	Backup bool `json:"backup"`
+}
+
+func (s *Service) applyProfile(w http.ResponseWriter, r *http.Request, name string) {
+	var req applyRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	opts := index.ApplyOptions{DryRun: req.DryRun, Backup: req.Backup}
+	if err := s.applyAndReload(r.Context(), name, opts); err != nil {
+		if errors.Is(err, errReloadFailed) {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		writeIndexError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// errReloadFailed wraps a Reloader failure so callers (HTTP and gRPC alike)
+// can distinguish "the profile was applied but headscale didn't pick it up"
+// from an ordinary index error, and report it as such instead of a generic
+// 500/Internal.
+var errReloadFailed = errors.New("headscale reload failed")
+
+// applyAndReload applies a profile and, unless this is a dry run, reloads
+// headscale with the result via s.reloader (if configured). It is shared by
+// the HTTP and gRPC Apply handlers so both surface a reload failure the same
+// way instead of just logging it, per request #chunk0-4.
+func (s *Service) applyAndReload(ctx context.Context, name string, opts index.ApplyOptions) error {
+	if err := s.idx.ApplyWithOptions(ctx, name, opts); err != nil {
+		return err
+	}
+
+	if opts.DryRun || s.reloader == nil {
+		return nil
+	}
+
+	data, err := s.idx.Get(ctx, name)
+	if err == nil {
+		err = s.reloader.Reload(data)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("profile", name).Msg("applied profile but failed to reload headscale")
+		return fmt.Errorf("%w: %v", errReloadFailed, err)
+	}
+
+	return nil
+}
+
+type renameRequest struct {
+	NewName string `json:"newName"`
+}
+
+func (s *Service) renameProfile(w http.ResponseWriter, r *http.Request, name string) {
+	var req renameRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.idx.RenameProfile(r.Context(), name, req.NewName); writeIndexError(w, err) {
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Service) diffProfile(w http.ResponseWriter, r *http.Request, name string) {
+	hunks, err := s.idx.DiffApply(r.Context(), name)
+	if writeIndexError(w, err) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(hunks)
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	return io.ReadAll(r.Body)
+}
+
+// writeIndexError translates index package errors into HTTP status codes
+// and writes the response if err is non-nil, reporting whether it did so.
+func writeIndexError(w http.ResponseWriter, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	switch {
+	case errors.Is(err, index.ErrProfileNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, index.ErrProfileExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, index.ErrInvalidACL):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/goodieshq/headscale-admin-acl/index"
+	"github.com/goodieshq/headscale-admin-acl/index/backend"
+)
+
+func newTestService(t *testing.T, opts ...Option) *Service {
+	t.Helper()
+
+	dir := t.TempDir()
+	be, err := backend.NewFSBackend(dir)
+	if err != nil {
+		t.Fatalf("NewFSBackend: %v", err)
+	}
+	idx, err := index.CreateNewIndex(dir, filepath.Join(dir, "acl.hujson"), be, nil)
+	if err != nil {
+		t.Fatalf("CreateNewIndex: %v", err)
+	}
+	return New(idx, opts...)
+}
+
+func TestRequireToken(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	h := requireToken("secret", ok)
+
+	cases := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"missing header", "", http.StatusUnauthorized},
+		{"wrong token", "Bearer wrong", http.StatusUnauthorized},
+		{"no bearer prefix", "secret", http.StatusUnauthorized},
+		{"correct token", "Bearer secret", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/profiles/p", nil)
+			if c.header != "" {
+				req.Header.Set("Authorization", c.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != c.want {
+				t.Fatalf("status = %d, want %d", rec.Code, c.want)
+			}
+		})
+	}
+}
+
+func TestHandleProfilesRouting(t *testing.T) {
+	svc := newTestService(t)
+
+	// PUT creates the profile.
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/profiles/p1", strings.NewReader("{}")))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	// GET reads it back.
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/profiles/p1", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Body.String() != "{}" {
+		t.Fatalf("GET body = %q, want %q", rec.Body.String(), "{}")
+	}
+
+	// An unknown action/method combination is rejected.
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profiles/p1", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("POST status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	// DELETE removes it.
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/profiles/p1", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("DELETE status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	// A missing profile name 404s before dispatch.
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/profiles/", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("empty name status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+// failingReloader always fails, to exercise applyProfile's reload-failure path.
+type failingReloader struct{ err error }
+
+func (r failingReloader) Reload([]byte) error { return r.err }
+
+func TestApplyProfileSurfacesReloadFailure(t *testing.T) {
+	reloadErr := errors.New("headscale unreachable")
+	svc := newTestService(t, WithReloader(failingReloader{err: reloadErr}))
+
+	rec := httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/profiles/p1", strings.NewReader(`{"acls":[]}`)))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("PUT status = %d, want %d: %s", rec.Code, http.StatusNoContent, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	svc.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/profiles/p1/apply", nil))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("apply status = %d, want %d: %s", rec.Code, http.StatusBadGateway, rec.Body.String())
+	}
+}
+
+func TestApplyAndReloadSkipsReloadOnDryRun(t *testing.T) {
+	called := false
+	svc := newTestService(t, WithReloader(funcReloader(func([]byte) error { called = true; return nil })))
+
+	ctx := context.Background()
+	if err := svc.idx.Set(ctx, "p1", []byte(`{"acls":[]}`)); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if err := svc.applyAndReload(ctx, "p1", index.ApplyOptions{DryRun: true}); err != nil {
+		t.Fatalf("applyAndReload: %v", err)
+	}
+	if called {
+		t.Fatal("dry run must not invoke the reloader")
+	}
+}
+
+type funcReloader func([]byte) error
+
+func (f funcReloader) Reload(data []byte) error { return f(data) }
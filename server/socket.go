@@ -0,0 +1,38 @@
+package server
+
+import (
+	"net"
+	"os"
+)
+
+// ListenUnix opens a Unix domain socket listener at path, suitable for
+// co-located deployments (e.g. an admin UI sharing a host with headscale).
+// Any stale socket left behind by a previous, uncleanly-terminated process
+// is removed first. The socket is chmod'd 0660 and, if gid is non-negative,
+// chowned to that group so only the configured group can connect.
+func ListenUnix(path string, gid int) (net.Listener, error) {
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Chmod(path, 0660); err != nil {
+		l.Close()
+		return nil, err
+	}
+
+	if gid >= 0 {
+		if err := os.Chown(path, -1, gid); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}